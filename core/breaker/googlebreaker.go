@@ -15,6 +15,8 @@ const (
 	buckets    = 40
 	k          = 1.5
 	protection = 5
+	// bufferedWindowSize是RollingWindow批量缓冲区的大小，用于削减高QPS下Add的锁竞争
+	bufferedWindowSize = 256
 )
 
 // googleBreaker is a netflixBreaker pattern from google.
@@ -32,10 +34,12 @@ type googleBreaker struct {
 
 func newGoogleBreaker() *googleBreaker {
 	bucketDuration := time.Duration(int64(window) / int64(buckets))
-	st := collection.NewRollingWindow(buckets, bucketDuration)
+	// 使用批量缓冲的RollingWindow，减少高并发场景下markSuccess/markFailure的锁竞争
+	st := collection.NewRollingWindow(buckets, bucketDuration,
+		collection.WithBatchedBuffer(bufferedWindowSize))
 	return &googleBreaker{
-		stat:  st,
-		k:     k,
+		stat: st,
+		k:    k,
 		//state: StateClosed,
 		proba: mathx.NewProba(),
 	}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+const (
+	// defaultBucketWidth is the width of a single time bucket used by the cleaner.
+	defaultBucketWidth = time.Second
+	// defaultBucketCount is the number of buckets the expiration ring holds.
+	defaultBucketCount = 60
+)
+
+type (
+	// ExpiryPolicy decides whether and when a cache entry expires.
+	ExpiryPolicy interface {
+		// Expired reports whether expireAt has passed, given the current time now.
+		Expired(now, expireAt time.Duration) bool
+		// ExpireAt computes the expiration time for a freshly set key with the given ttl.
+		ExpireAt(now time.Duration, ttl time.Duration) time.Duration
+	}
+
+	// fixedTTLPolicy expires every key after the same, fixed TTL.
+	fixedTTLPolicy struct {
+		ttl time.Duration
+	}
+
+	// variableTTLPolicy honors whatever TTL the caller passes to SetWithTTL/Touch.
+	variableTTLPolicy struct{}
+
+	// noExpiryPolicy never expires a key.
+	noExpiryPolicy struct{}
+
+	// bucketCleaner scans expiring keys in time-bucketed batches instead of per-key timers,
+	// giving O(k) work per tick where k is the number of keys due in that bucket.
+	// 将key按expireAt/bucketWidth哈希进N个时间桶中，cleaner每隔bucketWidth推进一次指针，
+	// 清理刚走过的那个桶，相比于为每个key维护定时器或堆，均摊开销是O(k)而非O(log n)。
+	// bucket里存的是key的真实expireAt：ttl超过bucketWidth*len(buckets)的key会被哈希到
+	// 同一个桶里多次，每次轮到这个桶时都要跟真实expireAt比较，没到期就重新挂回去，
+	// 而不是跟着这个桶一起被清空。
+	bucketCleaner struct {
+		lock        sync.Mutex
+		bucketWidth time.Duration
+		buckets     []map[interface{}]time.Duration
+	}
+)
+
+// NewFixedTTLPolicy returns an ExpiryPolicy that expires every key after ttl.
+func NewFixedTTLPolicy(ttl time.Duration) ExpiryPolicy {
+	return fixedTTLPolicy{ttl: ttl}
+}
+
+func (p fixedTTLPolicy) Expired(now, expireAt time.Duration) bool {
+	return now >= expireAt
+}
+
+func (p fixedTTLPolicy) ExpireAt(now, _ time.Duration) time.Duration {
+	return now + p.ttl
+}
+
+// NewVariableTTLPolicy returns an ExpiryPolicy driven by the ttl passed at SetWithTTL/Touch time.
+func NewVariableTTLPolicy() ExpiryPolicy {
+	return variableTTLPolicy{}
+}
+
+func (p variableTTLPolicy) Expired(now, expireAt time.Duration) bool {
+	return now >= expireAt
+}
+
+func (p variableTTLPolicy) ExpireAt(now, ttl time.Duration) time.Duration {
+	return now + ttl
+}
+
+// NewNoExpiryPolicy returns an ExpiryPolicy under which keys never expire.
+func NewNoExpiryPolicy() ExpiryPolicy {
+	return noExpiryPolicy{}
+}
+
+func (p noExpiryPolicy) Expired(_, _ time.Duration) bool {
+	return false
+}
+
+func (p noExpiryPolicy) ExpireAt(_, _ time.Duration) time.Duration {
+	return 0
+}
+
+func newBucketCleaner(bucketWidth time.Duration, bucketCount int) *bucketCleaner {
+	if bucketWidth <= 0 {
+		bucketWidth = defaultBucketWidth
+	}
+	if bucketCount < 1 {
+		bucketCount = defaultBucketCount
+	}
+
+	buckets := make([]map[interface{}]time.Duration, bucketCount)
+	for i := range buckets {
+		buckets[i] = make(map[interface{}]time.Duration)
+	}
+
+	return &bucketCleaner{
+		bucketWidth: bucketWidth,
+		buckets:     buckets,
+	}
+}
+
+func (c *bucketCleaner) index(expireAt time.Duration) int {
+	return int(expireAt/c.bucketWidth) % len(c.buckets)
+}
+
+func (c *bucketCleaner) track(key interface{}, expireAt time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.buckets[c.index(expireAt)][key] = expireAt
+}
+
+func (c *bucketCleaner) untrack(key interface{}, expireAt time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.buckets[c.index(expireAt)], key)
+}
+
+// run wakes every bucketWidth, advances the ring and hands the expired keys in the
+// bucket it just passed to onExpire. It returns a stop func that ends the loop.
+func (c *bucketCleaner) run(onExpire func(key interface{})) (stop func()) {
+	ticker := time.NewTicker(c.bucketWidth)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.tick(onExpire)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func (c *bucketCleaner) tick(onExpire func(key interface{})) {
+	now := timex.Now()
+	// 清理的是刚走过的上一个桶，它里面的key（除了跨整圈别名进来的）都已经到期
+	idx := c.index(now - c.bucketWidth)
+
+	c.lock.Lock()
+	bucket := c.buckets[idx]
+	c.buckets[idx] = make(map[interface{}]time.Duration)
+	c.lock.Unlock()
+
+	for key, expireAt := range bucket {
+		if expireAt <= now {
+			onExpire(key)
+			continue
+		}
+
+		// ttl超过了一整圈的时长，这个key只是被哈希别名到了这一轮的桶里，其实还没到期，
+		// 重新挂回它的真实桶（往往还是这一个桶），下一轮再检查，而不是直接丢弃跟踪
+		c.track(key, expireAt)
+	}
+}
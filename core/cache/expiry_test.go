@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+func TestBucketCleanerLongTTLEventuallyExpires(t *testing.T) {
+	// 桶宽10ms，3个桶，整圈周期只有30ms，ttl却有35ms，跨了一整圈，
+	// 用来验证别名进同一个桶的key不会被提前丢弃，最终仍会到期
+	cleaner := newBucketCleaner(10*time.Millisecond, 3)
+	expireAt := timex.Now() + 35*time.Millisecond
+	cleaner.track("k", expireAt)
+
+	expired := make(chan struct{}, 1)
+	stop := cleaner.run(func(key interface{}) {
+		if key == "k" {
+			select {
+			case expired <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer stop()
+
+	select {
+	case <-expired:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("key with ttl longer than one cleaner cycle was never expired")
+	}
+}
@@ -0,0 +1,383 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/syncx"
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+const (
+	// defaultCapacity is used when no WithCapacity option is given.
+	defaultCapacity = 1024
+	// maxFreq is the saturation point of an entry's access-frequency counter.
+	maxFreq = 3
+	// smallQueueRatio is the fraction of the total capacity reserved for the small FIFO.
+	smallQueueRatio = 0.1
+)
+
+type (
+	// S3FIFOCacheOption customizes a S3FIFOCache.
+	S3FIFOCacheOption func(cache *S3FIFOCache)
+
+	// S3FIFOCache is an in-memory cache that implements the S3-FIFO eviction policy,
+	// see https://s3fifo.com/ for the algorithm this is based on.
+	// 基于S3-FIFO淘汰算法实现的本地缓存，可作为Redis前面的本地缓存层使用
+	S3FIFOCache struct {
+		lock sync.Mutex
+		// small持有刚进入缓存的条目，占总容量的约10%
+		small *queue
+		// main持有从small晋升上来的条目，占总容量的约90%
+		main *queue
+		// ghost只保存曾经从small淘汰出去的key，不保存value，用于识别"曾经来过"的key
+		ghost *ghostQueue
+		items map[interface{}]*list.Element
+
+		// expiry决定了SetWithTTL/Touch写入的key何时过期，默认按调用方传入的ttl过期
+		expiry ExpiryPolicy
+		// cleaner按时间桶批量清理过期key，避免为每个key维护定时器
+		cleaner *bucketCleaner
+		// calls用于revalidate同一个过期key时去重并发请求，避免缓存击穿
+		calls syncx.SharedCalls
+		stop  func()
+	}
+
+	item struct {
+		key      interface{}
+		value    interface{}
+		freq     int32
+		expireAt time.Duration // zero means no expiry
+	}
+
+	queue struct {
+		capacity int
+		elements *list.List
+	}
+
+	ghostQueue struct {
+		capacity int
+		elements *list.List
+		index    map[interface{}]*list.Element
+	}
+)
+
+// NewS3FIFOCache returns a S3FIFOCache, use opts to customize the cache.
+func NewS3FIFOCache(opts ...S3FIFOCacheOption) *S3FIFOCache {
+	c := &S3FIFOCache{
+		items:   make(map[interface{}]*list.Element),
+		expiry:  NewVariableTTLPolicy(),
+		cleaner: newBucketCleaner(defaultBucketWidth, defaultBucketCount),
+		calls:   syncx.NewSharedCalls(),
+	}
+
+	capacity := defaultCapacity
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.main == nil {
+		c.setCapacity(capacity)
+	}
+	c.stop = c.cleaner.run(c.onBucketExpire)
+
+	return c
+}
+
+// Close stops the background cleaner goroutine. It's safe to keep using the
+// cache afterwards, only the bucket-based expiry sweeps will no longer run.
+func (c *S3FIFOCache) Close() {
+	c.stop()
+}
+
+// onBucketExpire is invoked by the cleaner for every key whose bucket just came due;
+// it re-checks the policy before evicting, since a key may have been refreshed since
+// it was tracked.
+func (c *S3FIFOCache) onBucketExpire(key interface{}) {
+	c.lock.Lock()
+	el, ok := c.items[key]
+	if !ok {
+		c.lock.Unlock()
+		return
+	}
+
+	it := el.Value.(*item)
+	expired := it.expireAt > 0 && c.expiry.Expired(timex.Now(), it.expireAt)
+	c.lock.Unlock()
+
+	if expired {
+		c.Delete(key)
+	}
+}
+
+// WithCapacity customizes a S3FIFOCache with the given total capacity.
+func WithCapacity(capacity int) S3FIFOCacheOption {
+	return func(c *S3FIFOCache) {
+		c.setCapacity(capacity)
+	}
+}
+
+func (c *S3FIFOCache) setCapacity(capacity int) {
+	if capacity < 1 {
+		panic("capacity must be greater than 0")
+	}
+
+	smallCap := int(float64(capacity) * smallQueueRatio)
+	if smallCap < 1 {
+		smallCap = 1
+	}
+	mainCap := capacity - smallCap
+	if mainCap < 1 {
+		mainCap = 1
+	}
+
+	c.small = newQueue(smallCap)
+	c.main = newQueue(mainCap)
+	c.ghost = newGhostQueue(mainCap)
+}
+
+func newQueue(capacity int) *queue {
+	return &queue{
+		capacity: capacity,
+		elements: list.New(),
+	}
+}
+
+func newGhostQueue(capacity int) *ghostQueue {
+	return &ghostQueue{
+		capacity: capacity,
+		elements: list.New(),
+		index:    make(map[interface{}]*list.Element),
+	}
+}
+
+// Get returns the value of the given key, bumping its access frequency on hit.
+// A key found but already past its TTL is treated as a miss.
+func (c *S3FIFOCache) Get(key interface{}) (interface{}, bool) {
+	c.lock.Lock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.lock.Unlock()
+		return nil, false
+	}
+
+	it := el.Value.(*item)
+	if it.expireAt > 0 && c.expiry.Expired(timex.Now(), it.expireAt) {
+		c.lock.Unlock()
+		c.Delete(key)
+		return nil, false
+	}
+
+	// 命中时提升freq，最多封顶到maxFreq
+	if it.freq < maxFreq {
+		it.freq++
+	}
+	val := it.value
+	c.lock.Unlock()
+
+	return val, true
+}
+
+// Set inserts or updates the value for the given key, without an expiry.
+func (c *S3FIFOCache) Set(key, value interface{}) {
+	c.set(key, value, 0)
+}
+
+// SetWithTTL inserts or updates the value for the given key, expiring it after ttl.
+func (c *S3FIFOCache) SetWithTTL(key, value interface{}, ttl time.Duration) {
+	c.set(key, value, c.expiry.ExpireAt(timex.Now(), ttl))
+}
+
+func (c *S3FIFOCache) set(key, value interface{}, expireAt time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		it := el.Value.(*item)
+		c.retrack(it, expireAt)
+		it.value = value
+		it.expireAt = expireAt
+		return
+	}
+
+	it := &item{key: key, value: value, expireAt: expireAt}
+	if expireAt > 0 {
+		c.cleaner.track(key, expireAt)
+	}
+
+	// ghost中存在该key，说明它最近被small淘汰过，直接晋升进main，不再经过small
+	if ge, ok := c.ghost.index[key]; ok {
+		c.ghost.remove(ge)
+		c.insertMain(it)
+		return
+	}
+
+	c.insertSmall(it)
+}
+
+// Touch updates the TTL of an existing key without changing its value,
+// returning false if the key isn't present.
+func (c *S3FIFOCache) Touch(key interface{}, newTTL time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+
+	it := el.Value.(*item)
+	expireAt := c.expiry.ExpireAt(timex.Now(), newTTL)
+	c.retrack(it, expireAt)
+	it.expireAt = expireAt
+
+	return true
+}
+
+func (c *S3FIFOCache) retrack(it *item, expireAt time.Duration) {
+	if it.expireAt > 0 {
+		c.cleaner.untrack(it.key, it.expireAt)
+	}
+	if expireAt > 0 {
+		c.cleaner.track(it.key, expireAt)
+	}
+}
+
+// Take returns the cached value for key if present and unexpired, otherwise it calls
+// fn to compute the value, caches it with the given ttl and returns it. Concurrent
+// Take calls for the same key share a single fn execution, protecting against
+// cache-stampede on a hot, just-expired key.
+func (c *S3FIFOCache) Take(key interface{}, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	if val, ok := c.Get(key); ok {
+		return val, nil
+	}
+
+	// %T:%v而不是单独%v，否则Take(5, ...)和Take("5", ...)这种不同类型但格式化后撞字符串的key
+	// 会被SharedCalls当成同一个key去重，后来者会拿到先来者的值，哪怕它们本来就不是同一个key
+	val, err := c.calls.Do(fmt.Sprintf("%T:%v", key, key), func() (interface{}, error) {
+		// double check，因为等待锁的过程中，可能有其他goroutine已经revalidate过了
+		if val, ok := c.Get(key); ok {
+			return val, nil
+		}
+
+		val, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		c.SetWithTTL(key, val, ttl)
+		return val, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// Delete removes the value for the given key.
+func (c *S3FIFOCache) Delete(key interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		it := el.Value.(*item)
+		if it.expireAt > 0 {
+			c.cleaner.untrack(key, it.expireAt)
+		}
+		c.small.elements.Remove(el)
+		c.main.elements.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of entries currently cached, excluding ghost keys.
+func (c *S3FIFOCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.items)
+}
+
+func (c *S3FIFOCache) insertSmall(it *item) {
+	el := c.small.elements.PushFront(it)
+	c.items[it.key] = el
+
+	for c.small.elements.Len() > c.small.capacity {
+		c.evictSmall()
+	}
+}
+
+func (c *S3FIFOCache) insertMain(it *item) {
+	el := c.main.elements.PushFront(it)
+	c.items[it.key] = el
+
+	for c.main.elements.Len() > c.main.capacity {
+		c.evictMain()
+	}
+}
+
+// evictSmall pops the tail of small: freq>0 promotes to main, otherwise the key goes to ghost.
+func (c *S3FIFOCache) evictSmall() {
+	tail := c.small.elements.Back()
+	if tail == nil {
+		return
+	}
+
+	c.small.elements.Remove(tail)
+	it := tail.Value.(*item)
+	delete(c.items, it.key)
+
+	if it.freq > 0 {
+		it.freq = 0
+		c.insertMain(it)
+	} else {
+		c.ghost.add(it.key)
+	}
+}
+
+// evictMain pops the tail of main: freq>0 gets a second chance with freq decremented,
+// otherwise the entry is dropped for good.
+func (c *S3FIFOCache) evictMain() {
+	for {
+		tail := c.main.elements.Back()
+		if tail == nil {
+			return
+		}
+
+		it := tail.Value.(*item)
+		if it.freq > 0 {
+			it.freq--
+			c.main.elements.MoveToFront(tail)
+			continue
+		}
+
+		c.main.elements.Remove(tail)
+		delete(c.items, it.key)
+		return
+	}
+}
+
+func (g *ghostQueue) add(key interface{}) {
+	if el, ok := g.index[key]; ok {
+		g.remove(el)
+	}
+
+	el := g.elements.PushFront(key)
+	g.index[key] = el
+
+	for g.elements.Len() > g.capacity {
+		tail := g.elements.Back()
+		if tail == nil {
+			break
+		}
+		g.remove(tail)
+	}
+}
+
+func (g *ghostQueue) remove(el *list.Element) {
+	delete(g.index, el.Value)
+	g.elements.Remove(el)
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestS3FIFOCacheSmallEvictionPromotesAccessedEntry(t *testing.T) {
+	// small容量=1(capacity*0.1向下取整后兜底为1)，main容量=1
+	c := NewS3FIFOCache(WithCapacity(2))
+	defer c.Close()
+
+	c.Set("a", 1)
+	// 命中一次，freq变为1，后面从small淘汰时应该被晋升进main而不是进ghost
+	c.Get("a")
+	c.Set("b", 2)
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a was accessed before eviction, it should have been promoted to main instead of dropped")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatal("b should still be cached")
+	}
+}
+
+func TestS3FIFOCacheSmallEvictionDropsUnaccessedEntry(t *testing.T) {
+	c := NewS3FIFOCache(WithCapacity(2))
+	defer c.Close()
+
+	c.Set("a", 1)
+	// a从未被Get过，freq仍为0，从small淘汰时应该直接进ghost而不是晋升
+	c.Set("b", 2)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a was never accessed, it should have been evicted to ghost, not kept")
+	}
+}
+
+func TestS3FIFOCacheTakeDedupesConcurrentCalls(t *testing.T) {
+	c := NewS3FIFOCache(WithCapacity(16))
+	defer c.Close()
+
+	var calls int32
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Take("k", 0, func() (interface{}, error) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			close(started)
+			<-release
+			return "v", nil
+		})
+	}()
+
+	<-started
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		val, err := c.Take("k", 0, func() (interface{}, error) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return "other", nil
+		})
+		if err != nil || val != "v" {
+			t.Errorf("got val %v, err %v, want v, nil", val, err)
+		}
+	}()
+
+	close(release)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Fatalf("fn was called %d times, want 1 (concurrent Take for the same key should dedupe)", calls)
+	}
+}
+
+func TestS3FIFOCacheTakeDoesNotCollideAcrossKeyTypes(t *testing.T) {
+	c := NewS3FIFOCache(WithCapacity(16))
+	defer c.Close()
+
+	intStarted := make(chan struct{})
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Take(5, 0, func() (interface{}, error) {
+			close(intStarted)
+			<-release
+			return "int-value", nil
+		})
+	}()
+
+	<-intStarted
+
+	// key 5 (int) 和 "5" (string) 格式化成字符串后长得一样，但不是同一个key，
+	// Take("5", ...)不应该被int-keyed的in-flight调用去重，拿到它的值
+	val, err := c.Take("5", 0, func() (interface{}, error) {
+		return "string-value", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if val != "string-value" {
+		t.Fatalf("got %v, want string-value (int key 5 and string key \"5\" collided)", val)
+	}
+
+	close(release)
+	wg.Wait()
+}
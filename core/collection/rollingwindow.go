@@ -2,6 +2,7 @@ package collection
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tal-tech/go-zero/core/timex"
@@ -21,7 +22,29 @@ type (
 		offset        int
 		ignoreCurrent bool
 		lastTime      time.Duration // start time of the last bucket
+		buffer        *batchBuffer  // 非nil时，Add走无锁缓冲，由buffer批量落盘到win
 	}
+
+	// batchBuffer实现了BP-Wrapper思路的无锁环形缓冲区，用来削峰高并发下Add带来的锁竞争，
+	// 缓冲区写满一半时由当前写入者触发一次drain，将缓冲内容批量合入RollingWindow的桶中。
+	batchBuffer struct {
+		mask    uint32
+		head    uint32
+		slots   []bufferSlot
+		drainMu sync.Mutex // 真正互斥的drain，drain()必须等到任何并发drain完成才返回，不能跳过
+	}
+
+	bufferSlot struct {
+		// filled的取值: slotEmpty(可写) -> slotWriting(写入中，尚不可读) -> slotFilled(可被drain读取)
+		filled int32
+		value  float64
+	}
+)
+
+const (
+	slotEmpty = iota
+	slotWriting
+	slotFilled
 )
 
 // NewRollingWindow returns a RollingWindow that with size buckets and time interval,
@@ -45,6 +68,23 @@ func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOpt
 
 // Add adds value to current bucket.
 func (rw *RollingWindow) Add(v float64) {
+	// 开启了批量缓冲模式时，走无锁路径，避免每次Add都竞争写锁
+	if rw.buffer != nil {
+		for {
+			full, collided := rw.buffer.add(v)
+			if collided {
+				// 环形缓冲转了一整圈还有没被消费的槽位，说明生产速度超过了drain速度，
+				// 强制同步drain腾出空间后重试，而不是覆盖掉尚未落盘的事件
+				rw.drain()
+				continue
+			}
+			if full {
+				rw.drain()
+			}
+			return
+		}
+	}
+
 	rw.lock.Lock()
 	defer rw.lock.Unlock()
 	// 根据时间重置桶及偏离量
@@ -53,8 +93,34 @@ func (rw *RollingWindow) Add(v float64) {
 	rw.win.add(rw.offset, v)
 }
 
+// drain flushes the buffered values into the underlying window under the write lock.
+// The whole collect-then-apply sequence runs under buffer.drainMu, so a concurrent
+// drain call blocks until this one has actually landed its values in the window,
+// instead of returning early with an empty batch while this one is still applying.
+func (rw *RollingWindow) drain() {
+	rw.buffer.drainMu.Lock()
+	defer rw.buffer.drainMu.Unlock()
+
+	values := rw.buffer.collect()
+	if len(values) == 0 {
+		return
+	}
+
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.updateOffset()
+	for _, v := range values {
+		rw.win.add(rw.offset, v)
+	}
+}
+
 // Reduce runs fn on all buckets, ignore current bucket if ignoreCurrent was set.
 func (rw *RollingWindow) Reduce(fn func(b *Bucket)) {
+	// Reduce前先触发一次drain，确保调用方看到的是合并了缓冲区内容的最新视图
+	if rw.buffer != nil {
+		rw.drain()
+	}
+
 	rw.lock.RLock()
 	defer rw.lock.RUnlock()
 
@@ -163,3 +229,75 @@ func IgnoreCurrentBucket() RollingWindowOption {
 		w.ignoreCurrent = true
 	}
 }
+
+// WithBatchedBuffer lets the RollingWindow buffer Add calls in a lock-free ring
+// buffer of the given size (rounded up to the next power of two), draining them
+// into the bucket window in bulk instead of taking the write lock on every Add.
+func WithBatchedBuffer(size int) RollingWindowOption {
+	return func(w *RollingWindow) {
+		w.buffer = newBatchBuffer(size)
+	}
+}
+
+func newBatchBuffer(size int) *batchBuffer {
+	if size < 1 {
+		panic("size must be greater than 0")
+	}
+
+	// 容量向上取整为2的幂，方便用位运算(& mask)代替取模
+	capacity := 1
+	for capacity < size {
+		capacity <<= 1
+	}
+
+	return &batchBuffer{
+		mask:  uint32(capacity - 1),
+		slots: make([]bufferSlot, capacity),
+	}
+}
+
+// add claims the next slot and writes v into it, reporting whether this write
+// completed half of the ring (it landed in the middle or the last slot) so the
+// caller can trigger a drain, and whether the
+// claim itself failed because that slot hadn't been drained yet (collided) --
+// in which case v was NOT stored and the caller must drain and retry.
+func (b *batchBuffer) add(v float64) (half, collided bool) {
+	head := atomic.AddUint32(&b.head, 1) - 1
+	slot := &b.slots[head&b.mask]
+	// 只有成功把槽位从empty抢占为writing，才说明它已经被上一轮drain过，可以安全写入，
+	// 否则这里直接返回collided，交给调用方强制drain后重试，而不是覆盖未读的旧值
+	if !atomic.CompareAndSwapInt32(&slot.filled, slotEmpty, slotWriting) {
+		return false, true
+	}
+
+	slot.value = v
+	// 写完value后才标记filled，drain侧只会在看到filled后才读取value，避免读到半写状态
+	atomic.StoreInt32(&slot.filled, slotFilled)
+
+	// 每写满半圈（中间槽位或最后一个槽位）就通知调用方drain一次
+	idx := head & b.mask
+	return idx == b.mask>>1 || idx == b.mask, false
+}
+
+// collect gathers and clears all filled slots. Callers are expected to hold
+// b.drainMu for the duration of collect plus whatever they do with the result,
+// so that a concurrent drain can't observe a partially-applied batch.
+func (b *batchBuffer) collect() []float64 {
+	values := make([]float64, 0, len(b.slots))
+	for i := range b.slots {
+		slot := &b.slots[i]
+		if atomic.LoadInt32(&slot.filled) != slotFilled {
+			continue
+		}
+
+		// value只有在filled==slotFilled时才稳定可读，一旦CAS把它放回slotEmpty，
+		// 下一个add()随时可能抢占并覆盖它，所以必须先把value拷出来再做CAS，
+		// 不能反过来——否则CAS赢了之后读到的可能已经是别的goroutine写入的新值
+		v := slot.value
+		if atomic.CompareAndSwapInt32(&slot.filled, slotFilled, slotEmpty) {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
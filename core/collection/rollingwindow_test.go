@@ -0,0 +1,81 @@
+package collection
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRollingWindowBatchedBufferConcurrentAdd(t *testing.T) {
+	const goroutines = 32
+	const perGoroutine = 500
+
+	rw := NewRollingWindow(10, time.Minute, WithBatchedBuffer(64))
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		// 每个goroutine用不同的value，collect()如果在CAS释放槽位后才读value，
+		// 槽位被另一个goroutine抢占写入新值就会让Sum对不上，Count仍然正确，
+		// 所以这里必须校验Sum，只校验Count发现不了这个问题
+		wg.Add(1)
+		go func(v float64) {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				rw.Add(v)
+			}
+		}(float64(i + 1))
+	}
+	wg.Wait()
+
+	var totalCount int64
+	var totalSum float64
+	rw.Reduce(func(b *Bucket) {
+		totalCount += b.Count
+		totalSum += b.Sum
+	})
+
+	if want := int64(goroutines * perGoroutine); totalCount != want {
+		t.Fatalf("got %d events merged into the window, want %d (buffered Add lost events)", totalCount, want)
+	}
+
+	wantSum := 0.0
+	for i := 0; i < goroutines; i++ {
+		wantSum += float64(i+1) * perGoroutine
+	}
+	if totalSum != wantSum {
+		t.Fatalf("got sum %v, want %v (a concurrent add corrupted a slot's value before collect read it)", totalSum, wantSum)
+	}
+}
+
+func benchmarkRollingWindowAdd(b *testing.B, goroutines int, buffered bool) {
+	var opts []RollingWindowOption
+	if buffered {
+		opts = append(opts, WithBatchedBuffer(256))
+	}
+	rw := NewRollingWindow(40, time.Millisecond*250, opts...)
+
+	per := b.N / goroutines
+	if per == 0 {
+		per = 1
+	}
+
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < per; i++ {
+				rw.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkRollingWindowAdd8(b *testing.B)           { benchmarkRollingWindowAdd(b, 8, false) }
+func BenchmarkRollingWindowAddBuffered8(b *testing.B)   { benchmarkRollingWindowAdd(b, 8, true) }
+func BenchmarkRollingWindowAdd32(b *testing.B)          { benchmarkRollingWindowAdd(b, 32, false) }
+func BenchmarkRollingWindowAddBuffered32(b *testing.B)  { benchmarkRollingWindowAdd(b, 32, true) }
+func BenchmarkRollingWindowAdd128(b *testing.B)         { benchmarkRollingWindowAdd(b, 128, false) }
+func BenchmarkRollingWindowAddBuffered128(b *testing.B) { benchmarkRollingWindowAdd(b, 128, true) }
@@ -2,6 +2,7 @@ package hash
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"sync"
@@ -20,6 +21,9 @@ const (
 type (
 	HashFunc func(data []byte) uint64
 
+	// ConsistentHashOption customizes a ConsistentHash.
+	ConsistentHashOption func(hash *ConsistentHash)
+
 	ConsistentHash struct {
 		hashFunc HashFunc
 		replicas int
@@ -27,6 +31,13 @@ type (
 		ring     map[uint64][]interface{}
 		nodes    map[string]lang.PlaceholderType
 		lock     sync.RWMutex
+
+		// loadFactor不大于1表示未启用带界负载，此时GetBounded等同于Get，见WithLoadFactor
+		loadFactor float64
+		// loads统计每个节点当前持有的活跃key数，用于带界负载的容量检查
+		loads map[string]int64
+		// totalLoad是loads中所有节点负载的总和，避免每次GetBounded都遍历loads求和
+		totalLoad int64
 	}
 )
 
@@ -34,7 +45,7 @@ func NewConsistentHash() *ConsistentHash {
 	return NewCustomConsistentHash(minReplicas, Hash)
 }
 
-func NewCustomConsistentHash(replicas int, fn HashFunc) *ConsistentHash {
+func NewCustomConsistentHash(replicas int, fn HashFunc, opts ...ConsistentHashOption) *ConsistentHash {
 	if replicas < minReplicas {
 		replicas = minReplicas
 	}
@@ -43,11 +54,26 @@ func NewCustomConsistentHash(replicas int, fn HashFunc) *ConsistentHash {
 		fn = Hash
 	}
 
-	return &ConsistentHash{
+	h := &ConsistentHash{
 		hashFunc: fn,
 		replicas: replicas,
 		ring:     make(map[uint64][]interface{}),
 		nodes:    make(map[string]lang.PlaceholderType),
+		loads:    make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// WithLoadFactor enables bounded-load consistent hashing with the given global
+// load factor c (c > 1): no node may hold more than ceil(c * totalKeys / numNodes)
+// active keys, see https://research.googleblog.com/2017/04/consistent-hashing-with-bounded-loads.html
+func WithLoadFactor(c float64) ConsistentHashOption {
+	return func(h *ConsistentHash) {
+		h.loadFactor = c
 	}
 }
 
@@ -127,6 +153,119 @@ func (h *ConsistentHash) Get(v interface{}) (interface{}, bool) {
 	}
 }
 
+// GetBounded is like Get, but additionally enforces consistent hashing with
+// bounded loads: starting from the node Get would have picked, it walks the
+// ring forward until it finds a node whose current load is under its cap,
+// wrapping around if necessary. WithLoadFactor must have been set with c > 1,
+// otherwise GetBounded behaves exactly like Get.
+func (h *ConsistentHash) GetBounded(v interface{}) (interface{}, bool) {
+	// 和Get一样只读ring/keys/loads/totalLoad，不写任何字段，用RLock而不是Lock，
+	// 否则高并发下GetBounded之间会互相串行等待，违背了带界负载本来就是为了削峰的初衷
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if len(h.ring) == 0 {
+		return nil, false
+	}
+
+	if h.loadFactor <= 1 {
+		return h.getNoLock(v)
+	}
+
+	hash := h.hashFunc([]byte(repr(v)))
+	index := sort.Search(len(h.keys), func(i int) bool {
+		return h.keys[i] >= hash
+	}) % len(h.keys)
+
+	// 从初始位置开始顺着环往前走，直到找到一个未超过负载上限的节点，最多走一整圈
+	for i := 0; i < len(h.keys); i++ {
+		idx := (index + i) % len(h.keys)
+		nodes := h.ring[h.keys[idx]]
+		node := h.pick(v, nodes)
+		if node == nil {
+			continue
+		}
+
+		if h.loadOk(repr(node)) {
+			return node, true
+		}
+	}
+
+	return nil, false
+}
+
+func (h *ConsistentHash) getNoLock(v interface{}) (interface{}, bool) {
+	hash := h.hashFunc([]byte(repr(v)))
+	index := sort.Search(len(h.keys), func(i int) bool {
+		return h.keys[i] >= hash
+	}) % len(h.keys)
+
+	return h.pick(v, h.ring[h.keys[index]]), len(h.ring) > 0
+}
+
+func (h *ConsistentHash) pick(v interface{}, nodes []interface{}) interface{} {
+	switch len(nodes) {
+	case 0:
+		return nil
+	case 1:
+		return nodes[0]
+	default:
+		innerIndex := h.hashFunc([]byte(innerRepr(v)))
+		pos := int(innerIndex % uint64(len(nodes)))
+		return nodes[pos]
+	}
+}
+
+// loadOk reports whether nodeRepr is still under its capacity,
+// ceil(loadFactor * (totalLoad+1) / numNodes), counting the key being placed.
+func (h *ConsistentHash) loadOk(nodeRepr string) bool {
+	if len(h.nodes) == 0 {
+		return false
+	}
+
+	capLimit := math.Ceil(h.loadFactor * float64(h.totalLoad+1) / float64(len(h.nodes)))
+	return float64(h.loads[nodeRepr]) < capLimit
+}
+
+// Inc increments the in-flight load for node, to be called once a key has
+// been routed to it via GetBounded.
+func (h *ConsistentHash) Inc(node interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	nodeRepr := repr(node)
+	h.loads[nodeRepr]++
+	h.totalLoad++
+}
+
+// Dec decrements the in-flight load for node, to be called once the caller is
+// done using the key that was routed to it via GetBounded.
+func (h *ConsistentHash) Dec(node interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	nodeRepr := repr(node)
+	if h.loads[nodeRepr] <= 0 {
+		return
+	}
+
+	h.loads[nodeRepr]--
+	h.totalLoad--
+}
+
+// Loads returns a snapshot of the current in-flight load per node.
+func (h *ConsistentHash) Loads() map[string]int64 {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	loads := make(map[string]int64, len(h.loads))
+	for k, v := range h.loads {
+		loads[k] = v
+	}
+
+	return loads
+}
+
 func (h *ConsistentHash) Remove(node interface{}) {
 	// 获取节点字符串
 	nodeRepr := repr(node)
@@ -139,6 +278,11 @@ func (h *ConsistentHash) Remove(node interface{}) {
 		return
 	}
 
+	// 删除前先把该节点的负载从totalLoad中扣除，否则totalLoad会在每次Remove后持续偏高，
+	// 导致loadOk算出的capLimit对所有节点都越来越宽松
+	h.totalLoad -= h.loads[nodeRepr]
+	delete(h.loads, nodeRepr)
+
 	// 遍历keys及ring，将对应数据全部删除掉
 	for i := 0; i < h.replicas; i++ {
 		hash := h.hashFunc([]byte(nodeRepr + strconv.Itoa(i)))
@@ -0,0 +1,85 @@
+package hash
+
+import (
+	"testing"
+)
+
+func TestConsistentHashGetBoundedRespectsLoadCap(t *testing.T) {
+	const numNodes = 3
+	const numKeys = 300
+
+	h := NewCustomConsistentHash(minReplicas, nil, WithLoadFactor(1.25))
+	for i := 0; i < numNodes; i++ {
+		h.Add(i)
+	}
+
+	for i := 0; i < numKeys; i++ {
+		node, ok := h.GetBounded(i)
+		if !ok {
+			t.Fatalf("GetBounded(%d) found no node under the load cap", i)
+		}
+		h.Inc(node)
+	}
+
+	loads := h.Loads()
+	if len(loads) != numNodes {
+		t.Fatalf("got %d nodes with recorded load, want %d", len(loads), numNodes)
+	}
+
+	capLimit := float64(numKeys) * 1.25 / float64(numNodes)
+	for node, load := range loads {
+		if float64(load) > capLimit+1 {
+			t.Fatalf("node %v carries %d keys, want at most ~%v (bounded-load cap not enforced)", node, load, capLimit)
+		}
+	}
+}
+
+func TestConsistentHashIncDec(t *testing.T) {
+	h := NewCustomConsistentHash(minReplicas, nil, WithLoadFactor(1.25))
+	h.Add("n1")
+
+	h.Inc("n1")
+	h.Inc("n1")
+	if got := h.Loads()["n1"]; got != 2 {
+		t.Fatalf("got load %d after two Inc, want 2", got)
+	}
+
+	h.Dec("n1")
+	if got := h.Loads()["n1"]; got != 1 {
+		t.Fatalf("got load %d after Dec, want 1", got)
+	}
+
+	// Dec不能让load变成负数
+	h.Dec("n1")
+	h.Dec("n1")
+	if got := h.Loads()["n1"]; got != 0 {
+		t.Fatalf("got load %d after Dec below zero, want floored at 0", got)
+	}
+}
+
+func TestConsistentHashRemoveDecrementsTotalLoad(t *testing.T) {
+	h := NewCustomConsistentHash(minReplicas, nil, WithLoadFactor(1.25))
+	h.Add("n1")
+	h.Add("n2")
+
+	h.Inc("n1")
+	h.Inc("n1")
+	h.Inc("n2")
+
+	h.Remove("n1")
+	h.Add("n3")
+
+	if h.totalLoad != 1 {
+		t.Fatalf("got totalLoad %d after removing n1, want 1 (only n2's load should remain)", h.totalLoad)
+	}
+
+	// 如果Remove没有先把n1的load从totalLoad里扣掉，totalLoad会一直偏高，
+	// loadOk算出的capLimit对所有节点都越来越宽松，GetBounded就形同虚设
+	node, ok := h.GetBounded("k")
+	if !ok {
+		t.Fatal("GetBounded found no node after Remove")
+	}
+	if node == "n1" {
+		t.Fatal("n1 was removed, GetBounded must not return it")
+	}
+}
@@ -0,0 +1,238 @@
+package hash
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+const (
+	// defaultPollInterval is how often a SentinelNodeSource re-checks the master set.
+	defaultPollInterval = time.Second
+)
+
+type (
+	// NodeConf describes a single consistent-hash node and the weight it should be
+	// added with, see ConsistentHash.AddWithWeight.
+	NodeConf struct {
+		Host   string
+		Weight int
+	}
+
+	// RebalanceHook is called after a NodeSource applies a topology change,
+	// so upstream connection pools can drain added/removed nodes gracefully.
+	RebalanceHook func(added, removed []string)
+
+	// NodeSource watches an external topology and keeps a ConsistentHash in sync
+	// with it, calling Add/Remove as nodes come and go.
+	NodeSource interface {
+		// Watch starts watching for topology changes against h, applying them as
+		// they're observed, until the returned stop func is called.
+		Watch(h *ConsistentHash) (stop func())
+		// OnRebalance registers a hook invoked after every applied topology change.
+		OnRebalance(hook RebalanceHook)
+	}
+
+	// SentinelClient abstracts a Redis Sentinel client down to the one thing a
+	// NodeSource needs from it: the current set of masters it watches.
+	SentinelClient interface {
+		Masters() ([]NodeConf, error)
+	}
+
+	pollingNodeSource struct {
+		fn          func() []NodeConf
+		interval    time.Duration
+		lock        sync.Mutex
+		weights     map[string]int
+		onRebalance RebalanceHook
+	}
+
+	// sentinelNodeSource is a NodeSource backed by Redis Sentinel, debouncing
+	// flapping topologies before they're applied to the ring.
+	// sentinelNodeSource监听哨兵上报的master集合，只有当同一份拓扑在settleInterval内
+	// 持续稳定出现时才会应用变更，避免因短暂抖动导致节点被反复Add/Remove
+	sentinelNodeSource struct {
+		client         SentinelClient
+		pollInterval   time.Duration
+		settleInterval time.Duration
+		lock           sync.Mutex
+		weights        map[string]int
+		pending        map[string]int
+		pendingSince   time.Duration
+		onRebalance    RebalanceHook
+	}
+)
+
+// PollingNodeSource returns a generic NodeSource that calls fn every interval to
+// fetch the current node set, so any backend (etcd, consul, a static file, ...)
+// can drive the ring just by implementing fn, without touching hash code.
+func PollingNodeSource(fn func() []NodeConf, interval time.Duration) NodeSource {
+	return &pollingNodeSource{
+		fn:       fn,
+		interval: interval,
+		weights:  make(map[string]int),
+	}
+}
+
+func (s *pollingNodeSource) OnRebalance(hook RebalanceHook) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onRebalance = hook
+}
+
+func (s *pollingNodeSource) Watch(h *ConsistentHash) (stop func()) {
+	ticker := time.NewTicker(s.interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.poll(h)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func (s *pollingNodeSource) poll(h *ConsistentHash) {
+	next := toWeights(s.fn())
+
+	s.lock.Lock()
+	prev := s.weights
+	s.weights = next
+	hook := s.onRebalance
+	s.lock.Unlock()
+
+	applyWeights(h, prev, next, hook)
+}
+
+// NewSentinelNodeSource returns a NodeSource that watches a Redis Sentinel's master
+// set, debouncing flapping topologies for at least settleInterval before they're
+// applied as Add/Remove calls against the ring.
+func NewSentinelNodeSource(client SentinelClient, settleInterval time.Duration) NodeSource {
+	if settleInterval <= 0 {
+		settleInterval = defaultPollInterval
+	}
+
+	return &sentinelNodeSource{
+		client:         client,
+		pollInterval:   defaultPollInterval,
+		settleInterval: settleInterval,
+		weights:        make(map[string]int),
+	}
+}
+
+func (s *sentinelNodeSource) OnRebalance(hook RebalanceHook) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onRebalance = hook
+}
+
+func (s *sentinelNodeSource) Watch(h *ConsistentHash) (stop func()) {
+	ticker := time.NewTicker(s.pollInterval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.poll(h)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func (s *sentinelNodeSource) poll(h *ConsistentHash) {
+	confs, err := s.client.Masters()
+	if err != nil {
+		// 哨兵暂时不可达时，保留上一次稳定的拓扑，等待下次轮询恢复
+		return
+	}
+
+	next := toWeights(confs)
+
+	s.lock.Lock()
+	if !equalWeights(next, s.pending) {
+		// 拓扑发生了变化，重新开始计时，等待它稳定settleInterval后才应用
+		s.pending = next
+		s.pendingSince = timex.Now()
+		s.lock.Unlock()
+		return
+	}
+
+	if timex.Since(s.pendingSince) < s.settleInterval {
+		s.lock.Unlock()
+		return
+	}
+
+	prev := s.weights
+	s.weights = next
+	hook := s.onRebalance
+	s.lock.Unlock()
+
+	applyWeights(h, prev, next, hook)
+}
+
+// applyWeights diffs prev against next, applying AddWithWeight/Remove calls
+// against h and invoking hook with the set of added and removed hosts.
+func applyWeights(h *ConsistentHash, prev, next map[string]int, hook RebalanceHook) {
+	var added, removed []string
+
+	for host, weight := range next {
+		if oldWeight, ok := prev[host]; !ok || oldWeight != weight {
+			h.AddWithWeight(host, weight)
+			if !ok {
+				added = append(added, host)
+			}
+		}
+	}
+
+	for host := range prev {
+		if _, ok := next[host]; !ok {
+			h.Remove(host)
+			removed = append(removed, host)
+		}
+	}
+
+	if hook != nil && (len(added) > 0 || len(removed) > 0) {
+		hook(added, removed)
+	}
+}
+
+func toWeights(confs []NodeConf) map[string]int {
+	weights := make(map[string]int, len(confs))
+	for _, conf := range confs {
+		weights[conf.Host] = conf.Weight
+	}
+
+	return weights
+}
+
+func equalWeights(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for host, weight := range a {
+		if bw, ok := b[host]; !ok || bw != weight {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,116 @@
+package hash
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPollingNodeSourceAppliesAddAndRemove(t *testing.T) {
+	var mu sync.Mutex
+	confs := []NodeConf{{Host: "a", Weight: TopWeight}}
+
+	source := PollingNodeSource(func() []NodeConf {
+		mu.Lock()
+		defer mu.Unlock()
+		return confs
+	}, time.Millisecond)
+
+	var addedMu sync.Mutex
+	var added, removed []string
+	source.OnRebalance(func(a, r []string) {
+		addedMu.Lock()
+		defer addedMu.Unlock()
+		added = append(added, a...)
+		removed = append(removed, r...)
+	})
+
+	h := NewConsistentHash()
+	stop := source.Watch(h)
+	defer stop()
+
+	waitUntil(t, func() bool {
+		_, ok := h.Get("k")
+		return ok
+	})
+
+	mu.Lock()
+	confs = []NodeConf{{Host: "b", Weight: TopWeight}}
+	mu.Unlock()
+
+	waitUntil(t, func() bool {
+		addedMu.Lock()
+		defer addedMu.Unlock()
+		for _, host := range removed {
+			if host == "a" {
+				return true
+			}
+		}
+		return false
+	})
+
+	node, ok := h.Get("k")
+	if !ok || node != "b" {
+		t.Fatalf("got node %v, ok %v, want b, true (ring should have rebalanced onto b)", node, ok)
+	}
+}
+
+func TestSentinelNodeSourceDebouncesFlappingTopology(t *testing.T) {
+	client := &fakeSentinelClient{}
+	source := NewSentinelNodeSource(client, 30*time.Millisecond)
+	// 内部轮询间隔固定为defaultPollInterval(1s)，测试里等不起，直接改小
+	source.(*sentinelNodeSource).pollInterval = time.Millisecond
+
+	h := NewConsistentHash()
+	stop := source.Watch(h)
+	defer stop()
+
+	client.setMasters([]NodeConf{{Host: "a", Weight: TopWeight}})
+	time.Sleep(10 * time.Millisecond)
+	client.setMasters([]NodeConf{{Host: "b", Weight: TopWeight}})
+	time.Sleep(10 * time.Millisecond)
+
+	// 还在settleInterval内反复抖动，不应该已经把b应用到环上
+	if _, ok := h.Get("k"); ok {
+		t.Fatal("a flapping topology inside settleInterval should not have been applied yet")
+	}
+
+	waitUntil(t, func() bool {
+		_, ok := h.Get("k")
+		return ok
+	})
+
+	node, ok := h.Get("k")
+	if !ok || node != "b" {
+		t.Fatalf("got node %v, ok %v, want the settled topology's b", node, ok)
+	}
+}
+
+type fakeSentinelClient struct {
+	lock    sync.Mutex
+	masters []NodeConf
+}
+
+func (c *fakeSentinelClient) setMasters(masters []NodeConf) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.masters = masters
+}
+
+func (c *fakeSentinelClient) Masters() ([]NodeConf, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.masters, nil
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("condition was never satisfied within the deadline")
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	cachecore "github.com/tal-tech/go-zero/core/cache"
+	"github.com/tal-tech/go-zero/core/hash"
+)
+
+// ErrNoAvailableNode is returned when the ring has no node to route a key to.
+var ErrNoAvailableNode = errors.New("no available cache node")
+
+const (
+	// defaultNodeSourcePollInterval is how often a Cluster re-checks its NodeConf
+	// slice for config-driven topology changes.
+	defaultNodeSourcePollInterval = time.Second
+	// defaultLocalTierCapacity sizes the S3-FIFO tier each Cluster keeps in front
+	// of its Redis-backed nodes.
+	defaultLocalTierCapacity = 1024
+)
+
+// Cluster routes keys across a set of Redis-backed cache nodes through a
+// consistent-hash ring, keeping an S3-FIFO local tier in front of them so hot
+// keys can be served without round-tripping to Redis at all.
+// Cluster把一组Redis节点用一致性哈希环路由起来，并在前面挂一层S3-FIFO本地缓存，
+// 热点key可以直接在本地命中，不必每次都打到Redis
+type Cluster struct {
+	ring      *hash.ConsistentHash
+	source    hash.NodeSource
+	stop      func()
+	local     *cachecore.S3FIFOCache
+	nodesLock sync.RWMutex
+	nodes     []NodeConf
+}
+
+// NewCluster builds a Cluster over nodes, starting a NodeSource that keeps the
+// ring in sync with whatever nodes SetNodes last set, as ClusterConf is reloaded.
+func NewCluster(nodes []NodeConf) *Cluster {
+	ring := hash.NewConsistentHash()
+	for _, node := range nodes {
+		weight := node.Weight
+		if weight < 0 {
+			weight = 0
+		}
+		if weight > 0 {
+			ring.AddWithWeight(node.Host, weight)
+		}
+	}
+
+	c := &Cluster{
+		ring:  ring,
+		nodes: nodes,
+		local: cachecore.NewS3FIFOCache(cachecore.WithCapacity(defaultLocalTierCapacity)),
+	}
+
+	// 传进去的是c.currentNodes这个访问器而不是nodes本身的快照，这样SetNodes更新的
+	// c.nodes才能被下一次轮询真正看到，否则这个NodeSource会永远轮询同一份旧配置
+	c.source = NewNodeSource(c.currentNodes, defaultNodeSourcePollInterval)
+	c.stop = c.source.Watch(ring)
+
+	return c
+}
+
+// SetNodes replaces the node set a Cluster routes across. The ring picks up the
+// change on the NodeSource's next poll, not synchronously.
+func (c *Cluster) SetNodes(nodes []NodeConf) {
+	c.nodesLock.Lock()
+	defer c.nodesLock.Unlock()
+	c.nodes = nodes
+}
+
+func (c *Cluster) currentNodes() []NodeConf {
+	c.nodesLock.RLock()
+	defer c.nodesLock.RUnlock()
+	return c.nodes
+}
+
+// Node returns the node a key is routed to by the consistent hash ring.
+func (c *Cluster) Node(key string) (string, bool) {
+	node, ok := c.ring.Get(key)
+	if !ok {
+		return "", false
+	}
+
+	return node.(string), true
+}
+
+// Take returns the local-tier value for key, calling fetch against the node Node(key)
+// routed to and caching the result for ttl if it's a miss.
+func (c *Cluster) Take(key string, ttl time.Duration, fetch func(node string) (interface{}, error)) (interface{}, error) {
+	return c.local.Take(key, ttl, func() (interface{}, error) {
+		node, ok := c.Node(key)
+		if !ok {
+			return nil, ErrNoAvailableNode
+		}
+
+		return fetch(node)
+	})
+}
+
+// Close stops the underlying NodeSource and local-tier cleaner goroutines.
+func (c *Cluster) Close() {
+	c.stop()
+	c.local.Close()
+}
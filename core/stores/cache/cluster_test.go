@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClusterNodeRoutesAcrossNodes(t *testing.T) {
+	c := NewCluster([]NodeConf{{Host: "a", Weight: TopWeightForTest}})
+	defer c.Close()
+
+	node, ok := c.Node("k")
+	if !ok || node != "a" {
+		t.Fatalf("got node %v, ok %v, want a, true", node, ok)
+	}
+}
+
+func TestClusterSetNodesIsPickedUpByNodeSource(t *testing.T) {
+	c := NewCluster([]NodeConf{{Host: "a", Weight: TopWeightForTest}})
+	defer c.Close()
+
+	c.SetNodes([]NodeConf{{Host: "b", Weight: TopWeightForTest}})
+
+	// defaultNodeSourcePollInterval是1s，多等几轮确保不是偶发超时
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if node, ok := c.Node("k"); ok && node == "b" {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Cluster never rebalanced onto the node set by SetNodes (NodeSource must poll live config, not a one-time snapshot)")
+}
+
+func TestClusterTakeFetchesAndCachesOnMiss(t *testing.T) {
+	c := NewCluster([]NodeConf{{Host: "a", Weight: TopWeightForTest}})
+	defer c.Close()
+
+	var calls int
+	val, err := c.Take("k", time.Minute, func(node string) (interface{}, error) {
+		calls++
+		return "v-" + node, nil
+	})
+	if err != nil || val != "v-a" {
+		t.Fatalf("got val %v, err %v, want v-a, nil", val, err)
+	}
+
+	val, err = c.Take("k", time.Minute, func(node string) (interface{}, error) {
+		calls++
+		return "v-" + node, nil
+	})
+	if err != nil || val != "v-a" {
+		t.Fatalf("got val %v, err %v, want v-a, nil", val, err)
+	}
+	if calls != 1 {
+		t.Fatalf("fetch was called %d times, want 1 (second Take should hit the local tier)", calls)
+	}
+}
+
+func TestClusterTakeReturnsErrNoAvailableNode(t *testing.T) {
+	c := NewCluster(nil)
+	defer c.Close()
+
+	_, err := c.Take("k", time.Minute, func(node string) (interface{}, error) {
+		t.Fatal("fetch must not run when there's no node to route to")
+		return nil, nil
+	})
+	if err != ErrNoAvailableNode {
+		t.Fatalf("got err %v, want ErrNoAvailableNode", err)
+	}
+}
+
+// TopWeightForTest mirrors hash.TopWeight without importing core/hash just for a constant.
+const TopWeightForTest = 100
@@ -1,6 +1,11 @@
 package cache
 
-import "strings"
+import (
+	"strings"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/hash"
+)
 
 const keySeparator = ","
 
@@ -23,3 +28,23 @@ func TotalWeights(c []NodeConf) int {
 func formatKeys(keys []string) string {
 	return strings.Join(keys, keySeparator)
 }
+
+// NewNodeSource adapts getNodes, which must return the current []NodeConf on every
+// call (e.g. reading a live Cluster.nodes rather than a snapshot taken once), into a
+// hash.NodeSource, so the cache cluster's consistent hash ring can be kept in sync
+// with config-driven node changes without bespoke wiring.
+func NewNodeSource(getNodes func() []NodeConf, interval time.Duration) hash.NodeSource {
+	return hash.PollingNodeSource(func() []hash.NodeConf {
+		c := getNodes()
+		nodes := make([]hash.NodeConf, 0, len(c))
+		for _, node := range c {
+			weight := node.Weight
+			if weight < 0 {
+				weight = 0
+			}
+			nodes = append(nodes, hash.NodeConf{Host: node.Host, Weight: weight})
+		}
+
+		return nodes
+	}, interval)
+}
@@ -0,0 +1,110 @@
+package syncx
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs a collection of goroutines and collects their first error,
+// modeled after golang.org/x/sync/errgroup. It complements SharedCalls
+// (single-flight) with a fan-out primitive that unifies cancellation.
+// Group组合了一组并发执行的任务，收集首个出现的错误，并在该错误发生时取消context，
+// 与处理单次调用共享结果的SharedCalls互补，适合处理多个相互独立的并发调用
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+	sem    chan struct{}
+}
+
+// NewGroup returns a Group with no context and no concurrency limit.
+func NewGroup() *Group {
+	return new(Group)
+}
+
+// WithContext returns a Group and an associated Context derived from ctx,
+// the derived Context is canceled the first time a function passed to Go
+// returns a non-nil error or the first time Wait returns, whichever occurs
+// first.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// WithLimit caps the number of goroutines spawned by Go/TryGo to n,
+// using a semaphore channel. n must be greater than 0.
+func (g *Group) WithLimit(n int) *Group {
+	if n < 1 {
+		panic("n must be greater than 0")
+	}
+
+	g.sem = make(chan struct{}, n)
+	return g
+}
+
+// Go calls the given function in a new goroutine, blocking until a slot is
+// available if WithLimit was used. The first call to return a non-nil error
+// cancels the group's context, if any, and that error is returned by Wait.
+func (g *Group) Go(fn func() error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		g.run(fn)
+	}()
+}
+
+// TryGo calls the given function in a new goroutine only if a slot is
+// immediately available, reporting whether it was able to do so. Without
+// WithLimit, TryGo always succeeds.
+func (g *Group) TryGo(fn func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		g.run(fn)
+	}()
+
+	return true
+}
+
+func (g *Group) run(fn func() error) {
+	if err := fn(); err != nil {
+		g.once.Do(func() {
+			g.err = err
+			if g.cancel != nil {
+				g.cancel()
+			}
+		})
+	}
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// Wait blocks until all function calls from Go/TryGo have returned, then
+// returns the first non-nil error, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	return g.err
+}
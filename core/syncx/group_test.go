@@ -0,0 +1,122 @@
+package syncx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupWaitReturnsFirstError(t *testing.T) {
+	g := NewGroup()
+	wantErr := errors.New("boom")
+
+	g.Go(func() error { return nil })
+	g.Go(func() error { return wantErr })
+
+	if err := g.Wait(); err != wantErr {
+		t.Fatalf("got err %v, want %v", err, wantErr)
+	}
+}
+
+func TestGroupWithContextCancelsOnFirstError(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	wantErr := errors.New("boom")
+
+	started := make(chan struct{})
+	g.Go(func() error {
+		close(started)
+		return wantErr
+	})
+
+	otherDone := make(chan error, 1)
+	g.Go(func() error {
+		<-started
+		select {
+		case <-ctx.Done():
+			otherDone <- ctx.Err()
+		case <-time.After(time.Second):
+			otherDone <- nil
+		}
+		return nil
+	})
+
+	if err := g.Wait(); err != wantErr {
+		t.Fatalf("got err %v, want %v", err, wantErr)
+	}
+	if err := <-otherDone; err != context.Canceled {
+		t.Fatalf("got ctx err %v, want context.Canceled (one goroutine's error must cancel the group's context)", err)
+	}
+}
+
+func TestGroupWithLimitBoundsConcurrency(t *testing.T) {
+	const n = 5
+	g := NewGroup().WithLimit(2)
+
+	var running, maxRunning int32
+	var mu sync.Mutex
+	release := make(chan struct{})
+	finished := make(chan struct{}, n)
+
+	// Go()在拿不到信号量槽位时会同步阻塞调用它的goroutine，所以分发本身也要放到
+	// 独立的goroutine里，否则limit=2时第3次Go()会卡住这里，永远等不到下面的close(release)
+	go func() {
+		for i := 0; i < n; i++ {
+			g.Go(func() error {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+
+				<-release
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				finished <- struct{}{}
+				return nil
+			})
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	// 等全部n个任务都真正跑完、各自的wg.Add都已经发生之后再调用Wait，
+	// 避免Wait跟尚未执行到的Go()里的wg.Add发生竞争
+	for i := 0; i < n; i++ {
+		<-finished
+	}
+
+	if err := g.Wait(); err != nil {
+		t.Fatalf("got err %v, want nil", err)
+	}
+	if maxRunning > 2 {
+		t.Fatalf("got max concurrent goroutines %d, want at most 2 (WithLimit not enforced)", maxRunning)
+	}
+}
+
+func TestGroupTryGoReportsWhenNoSlot(t *testing.T) {
+	g := NewGroup().WithLimit(1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	g.Go(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	<-started
+	if g.TryGo(func() error { return nil }) {
+		t.Fatal("TryGo should have reported no slot available while the single slot is held")
+	}
+
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatalf("got err %v, want nil", err)
+	}
+}
@@ -1,6 +1,12 @@
 package syncx
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+)
 
 type (
 	// SharedCalls lets the concurrent calls with the same key to share the call result.
@@ -13,16 +19,28 @@ type (
 	SharedCalls interface {
 		Do(key string, fn func() (interface{}, error)) (interface{}, error)
 		DoEx(key string, fn func() (interface{}, error)) (interface{}, bool, error)
+		// DoCtx is like Do, but a late-arriving caller's own ctx being canceled makes
+		// only that caller's wait return early with ctx.Err(), it never affects the
+		// in-flight leader that's actually executing fn.
+		DoCtx(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error)
+		// DoWithFreshness is like Do, but a successfully completed call's result is kept
+		// around for ttl and served to callers within that window without calling fn again,
+		// mitigating a thundering herd past the in-flight window Do alone covers.
+		// A call that returned an error is not kept, the next caller runs fn again.
+		DoWithFreshness(key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error)
 	}
 
 	call struct {
 		wg  sync.WaitGroup
 		val interface{}
 		err error
+		// finishedAt记录调用完成的时间，仅DoWithFreshness使用，用core/timex避免热路径上的系统调用
+		finishedAt time.Duration
 	}
 
 	sharedGroup struct {
 		calls map[string]*call
+		fresh map[string]*call
 		lock  sync.Mutex
 	}
 )
@@ -31,6 +49,7 @@ type (
 func NewSharedCalls() SharedCalls {
 	return &sharedGroup{
 		calls: make(map[string]*call),
+		fresh: make(map[string]*call),
 	}
 }
 
@@ -56,6 +75,90 @@ func (g *sharedGroup) DoEx(key string, fn func() (interface{}, error)) (val inte
 	return c.val, true, c.err
 }
 
+func (g *sharedGroup) DoCtx(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	// 注意：这里不能用createCall，它会在发现已有call时直接阻塞在c.wg.Wait()上，
+	// 导致下面的ctx-aware等待形同虚设。loadOrCreateCall只负责拿到call，不做任何等待
+	c, loaded := g.loadOrCreateCall(key)
+	if loaded {
+		// 被阻塞的后来者可能等不及leader执行完，一旦ctx被取消就提前返回，但不会影响leader的执行
+		return g.waitCtx(ctx, c)
+	}
+
+	g.makeCall(c, key, fn)
+	return c.val, c.err
+}
+
+// loadOrCreateCall returns the in-flight call for key without waiting on it,
+// creating and registering a new one if none exists yet.
+func (g *sharedGroup) loadOrCreateCall(key string) (c *call, loaded bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		return c, true
+	}
+
+	c = new(call)
+	c.wg.Add(1)
+	g.calls[key] = c
+
+	return c, false
+}
+
+func (g *sharedGroup) waitCtx(ctx context.Context, c *call) (interface{}, error) {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *sharedGroup) DoWithFreshness(key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	g.lock.Lock()
+	if c, ok := g.fresh[key]; ok {
+		// 结果仍在ttl有效期内，直接返回，不再执行fn
+		if timex.Since(c.finishedAt) < ttl {
+			g.lock.Unlock()
+			return c.val, c.err
+		}
+		delete(g.fresh, key)
+	}
+
+	if c, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+
+	c := new(call)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	c.val, c.err = fn()
+	c.finishedAt = timex.Now()
+
+	g.lock.Lock()
+	delete(g.calls, key)
+	// 只有成功的结果才值得缓存进fresh；一次瞬时失败如果也被缓存ttl那么久，
+	// 后续所有调用者都会直接拿到这个错误而不会重试fn，比完全不缓存还糟糕，
+	// 所以失败时只把call从calls摘掉，让下一个调用者重新走一次fn
+	if c.err == nil {
+		g.fresh[key] = c
+	}
+	g.lock.Unlock()
+	c.wg.Done()
+
+	return c.val, c.err
+}
+
 func (g *sharedGroup) createCall(key string) (c *call, done bool) {
 	g.lock.Lock()
 	// 判断当前是否已经有人在执行该调用， 如果有的话，阻塞直到对方执行完后，直接获取它执行的结果
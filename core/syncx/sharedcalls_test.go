@@ -0,0 +1,76 @@
+package syncx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoCtxLateArrivalCanceledIndependently(t *testing.T) {
+	g := NewSharedCalls()
+
+	leaderStarted := make(chan struct{})
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.DoCtx(context.Background(), "k", func() (interface{}, error) {
+			close(leaderStarted)
+			<-release
+			return "v", nil
+		})
+	}()
+
+	<-leaderStarted
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, err := g.DoCtx(ctx, "k", func() (interface{}, error) {
+		t.Fatal("a follower sharing the leader's call must not execute fn itself")
+		return nil, nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("DoCtx with an already-canceled context took %v, should return immediately without waiting on the leader", elapsed)
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestDoWithFreshnessDoesNotCacheErrors(t *testing.T) {
+	g := NewSharedCalls()
+	wantErr := errors.New("transient failure")
+
+	calls := 0
+	_, err := g.DoWithFreshness("k", time.Minute, func() (interface{}, error) {
+		calls++
+		return nil, wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got err %v, want %v", err, wantErr)
+	}
+
+	// 上一次调用失败了，不该被缓存进fresh，这次必须重新执行fn而不是直接拿到上次的错误
+	val, err := g.DoWithFreshness("k", time.Minute, func() (interface{}, error) {
+		calls++
+		return "v", nil
+	})
+	if err != nil {
+		t.Fatalf("got err %v, want nil", err)
+	}
+	if val != "v" {
+		t.Fatalf("got val %v, want v", val)
+	}
+	if calls != 2 {
+		t.Fatalf("fn was called %d times, want 2 (a failed call must not poison later callers for ttl)", calls)
+	}
+}